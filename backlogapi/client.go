@@ -0,0 +1,261 @@
+// Package backlogapi is a small typed client for the parts of the Backlog
+// REST API (https://developer.nulab.com/docs/backlog/) that gitb needs in
+// order to act on pull requests and issues directly, instead of only
+// opening them in a browser.
+package backlogapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Client talks to a single Backlog space using an API key.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// NewClient builds a Client for the space "<spaceKey>.<domain>" authenticated
+// with apiKey. apiKey is typically read from the BACKLOG_API_KEY environment
+// variable or a config file by the caller.
+func NewClient(domain, spaceKey, apiKey string) *Client {
+	return &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    fmt.Sprintf("https://%s.%s/api/v2", spaceKey, domain),
+		apiKey:     apiKey,
+	}
+}
+
+// PullRequest is a subset of the fields returned by the Backlog pull
+// request API that gitb cares about.
+type PullRequest struct {
+	Number      int    `json:"number"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Base        string `json:"base"`
+	Branch      string `json:"branch"`
+	Status      struct {
+		Name string `json:"name"`
+	} `json:"status"`
+	CreatedUser struct {
+		Name string `json:"name"`
+	} `json:"createdUser"`
+	Updated string `json:"updated"`
+}
+
+// Issue is a subset of the fields returned by the Backlog issue API.
+type Issue struct {
+	ID          int    `json:"id"`
+	IssueKey    string `json:"issueKey"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Status      struct {
+		Name string `json:"name"`
+	} `json:"status"`
+	Updated string `json:"updated"`
+}
+
+// Project is a subset of the fields returned by the Backlog project API,
+// used to resolve a project's numeric ID from its key.
+type Project struct {
+	ID int `json:"id"`
+}
+
+// Comment is a single comment on a pull request or issue.
+type Comment struct {
+	Content     string `json:"content"`
+	CreatedUser struct {
+		Name string `json:"name"`
+	} `json:"createdUser"`
+	Created string `json:"created"`
+}
+
+// DiffSummary is the file change counts for a pull request's diff.
+type DiffSummary struct {
+	Added   int `json:"added"`
+	Changed int `json:"changed"`
+	Deleted int `json:"deleted"`
+}
+
+// CreatePullRequestParams are the fields accepted when opening a pull
+// request through the API.
+type CreatePullRequestParams struct {
+	Base        string
+	Branch      string
+	Summary     string
+	Description string
+}
+
+// ListPullRequests returns the pull requests for projectKey/repoName,
+// optionally filtered by statusID (0 means "all", matching PRStatus.Int()).
+func (c *Client) ListPullRequests(ctx context.Context, projectKey, repoName string, statusID int) ([]PullRequest, error) {
+	q := url.Values{}
+	if statusID != 0 {
+		q.Set("statusId[]", strconv.Itoa(statusID))
+	}
+	var prs []PullRequest
+	path := fmt.Sprintf("/projects/%s/git/repositories/%s/pullRequests", projectKey, repoName)
+	if err := c.get(ctx, path, q, &prs); err != nil {
+		return nil, errors.Wrap(err, "list pull requests")
+	}
+	return prs, nil
+}
+
+// CreatePullRequest opens a new pull request from params.Branch into
+// params.Base.
+func (c *Client) CreatePullRequest(ctx context.Context, projectKey, repoName string, params CreatePullRequestParams) (*PullRequest, error) {
+	form := url.Values{}
+	form.Set("summary", params.Summary)
+	form.Set("description", params.Description)
+	form.Set("base", params.Base)
+	form.Set("branch", params.Branch)
+
+	var pr PullRequest
+	path := fmt.Sprintf("/projects/%s/git/repositories/%s/pullRequests", projectKey, repoName)
+	if err := c.post(ctx, path, form, &pr); err != nil {
+		return nil, errors.Wrap(err, "create pull request")
+	}
+	return &pr, nil
+}
+
+// ClosePullRequest moves the pull request to the "Closed" status.
+func (c *Client) ClosePullRequest(ctx context.Context, projectKey, repoName string, number int) error {
+	form := url.Values{}
+	form.Set("statusId", strconv.Itoa(int(closedStatusID)))
+
+	path := fmt.Sprintf("/projects/%s/git/repositories/%s/pullRequests/%d", projectKey, repoName, number)
+	return errors.Wrap(c.patch(ctx, path, form, &PullRequest{}), "close pull request")
+}
+
+// AddPullRequestComment posts a comment on the given pull request.
+func (c *Client) AddPullRequestComment(ctx context.Context, projectKey, repoName string, number int, comment string) error {
+	form := url.Values{}
+	form.Set("content", comment)
+
+	path := fmt.Sprintf("/projects/%s/git/repositories/%s/pullRequests/%d/comments", projectKey, repoName, number)
+	return errors.Wrap(c.post(ctx, path, form, &struct{}{}), "comment on pull request")
+}
+
+// ListPullRequestComments returns the comments posted on a pull request,
+// oldest first, for use in the dashboard's detail view.
+func (c *Client) ListPullRequestComments(ctx context.Context, projectKey, repoName string, number int) ([]Comment, error) {
+	var comments []Comment
+	path := fmt.Sprintf("/projects/%s/git/repositories/%s/pullRequests/%d/comments", projectKey, repoName, number)
+	if err := c.get(ctx, path, nil, &comments); err != nil {
+		return nil, errors.Wrap(err, "list pull request comments")
+	}
+	return comments, nil
+}
+
+// PullRequestDiffSummary returns the added/changed/deleted file counts for
+// a pull request's diff, for use in the dashboard's detail view.
+func (c *Client) PullRequestDiffSummary(ctx context.Context, projectKey, repoName string, number int) (*DiffSummary, error) {
+	var diff DiffSummary
+	path := fmt.Sprintf("/projects/%s/git/repositories/%s/pullRequests/%d/diff", projectKey, repoName, number)
+	if err := c.get(ctx, path, nil, &diff); err != nil {
+		return nil, errors.Wrap(err, "pull request diff summary")
+	}
+	return &diff, nil
+}
+
+// GetProject resolves a project's numeric ID from its key or ID
+// (projectIdOrKey), for use where the Backlog API requires the former.
+func (c *Client) GetProject(ctx context.Context, projectIdOrKey string) (*Project, error) {
+	var project Project
+	path := fmt.Sprintf("/projects/%s", projectIdOrKey)
+	if err := c.get(ctx, path, nil, &project); err != nil {
+		return nil, errors.Wrap(err, "get project")
+	}
+	return &project, nil
+}
+
+// ListIssues returns the issues for the project with the given numeric
+// projectID, optionally filtered by statusIds (empty means "all").
+// projectId[] takes a numeric project ID, not a project key.
+func (c *Client) ListIssues(ctx context.Context, projectID int, statusIds []int) ([]Issue, error) {
+	q := url.Values{}
+	q.Set("projectId[]", strconv.Itoa(projectID))
+	for _, id := range statusIds {
+		q.Add("statusId[]", strconv.Itoa(id))
+	}
+	var issues []Issue
+	if err := c.get(ctx, "/issues", q, &issues); err != nil {
+		return nil, errors.Wrap(err, "list issues")
+	}
+	return issues, nil
+}
+
+// AddIssueComment posts a comment on the issue identified by issueIdOrKey
+// (e.g. "PROJ-123").
+func (c *Client) AddIssueComment(ctx context.Context, issueIdOrKey, comment string) error {
+	form := url.Values{}
+	form.Set("content", comment)
+
+	path := fmt.Sprintf("/issues/%s/comments", issueIdOrKey)
+	return errors.Wrap(c.post(ctx, path, form, &struct{}{}), "comment on issue")
+}
+
+// closedStatusID is the Backlog pull request status ID for "Closed".
+// Backlog defines 1=Open, 2=Closed, 3=Merged.
+const closedStatusID = 2
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("apiKey", c.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, out)
+}
+
+func (c *Client) post(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path+"?apiKey="+url.QueryEscape(c.apiKey), bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, out)
+}
+
+func (c *Client) patch(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.baseURL+path+"?apiKey="+url.QueryEscape(c.apiKey), bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		if len(apiErr.Errors) > 0 {
+			return errors.Errorf("backlog api: %s (status %d)", apiErr.Errors[0].Message, resp.StatusCode)
+		}
+		return errors.Errorf("backlog api: unexpected status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
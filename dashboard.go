@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/takuji/gitb/backlogapi"
+)
+
+// dashboardTab is one pane of the dashboard bubbletea model.
+type dashboardTab int
+
+const (
+	tabBranches dashboardTab = iota
+	tabPullRequests
+	tabIssues
+	tabCommits
+)
+
+var dashboardTabs = []dashboardTab{tabBranches, tabPullRequests, tabIssues, tabCommits}
+
+func (t dashboardTab) String() string {
+	switch t {
+	case tabBranches:
+		return "Branches"
+	case tabPullRequests:
+		return "Pull Requests"
+	case tabIssues:
+		return "Issues"
+	case tabCommits:
+		return "Commits"
+	default:
+		return "?"
+	}
+}
+
+// dashboardRow is a single line in one of the dashboard's panes, already
+// formatted for display plus whatever is needed to act on it.
+type dashboardRow struct {
+	title   string
+	status  string
+	author  string
+	updated string
+	detail  string // branch name, PR number, issue key or commit hash, used by enter
+}
+
+func (r dashboardRow) matches(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(r.title), strings.ToLower(filter))
+}
+
+type dashboardRowsMsg struct {
+	tab  dashboardTab
+	rows []dashboardRow
+	err  error
+}
+
+// dashboard is a full-screen bubbletea model with one pane per
+// dashboardTab, backed by the Backlog API client. prSelector remains a
+// lighter-weight special case used by OpenPullRequestCtx when only a single
+// pull-request pick is needed.
+type dashboard struct {
+	ctx     context.Context
+	backlog *BacklogRepository
+	tab     dashboardTab
+	rows    map[dashboardTab][]dashboardRow
+	cursor  map[dashboardTab]int
+	loading map[dashboardTab]bool
+	errs    map[dashboardTab]error
+
+	filtering bool
+	filter    string
+
+	detail      *dashboardRow
+	detailDiff  *backlogapi.DiffSummary
+	detailLines []string
+}
+
+// NewDashboard builds the gitb tui model for backlog. ctx is used for every
+// network/exec round-trip the dashboard makes while it's running, so
+// cancelling it (e.g. via RootContext's Ctrl-C handling) unblocks a stuck
+// fetch instead of leaving the TUI hung.
+func NewDashboard(ctx context.Context, backlog *BacklogRepository) dashboard {
+	return dashboard{
+		ctx:     ctx,
+		backlog: backlog,
+		tab:     tabBranches,
+		rows:    make(map[dashboardTab][]dashboardRow),
+		cursor:  make(map[dashboardTab]int),
+		loading: make(map[dashboardTab]bool),
+		errs:    make(map[dashboardTab]error),
+	}
+}
+
+func (d dashboard) Init() tea.Cmd {
+	d.loading[tabBranches] = true
+	return d.loadTab(tabBranches)
+}
+
+func (d dashboard) loadTab(tab dashboardTab) tea.Cmd {
+	ctx := d.ctx
+	backlog := d.backlog
+	return func() tea.Msg {
+		rows, err := fetchDashboardRows(ctx, backlog, tab)
+		return dashboardRowsMsg{tab: tab, rows: rows, err: err}
+	}
+}
+
+func fetchDashboardRows(ctx context.Context, b *BacklogRepository, tab dashboardTab) ([]dashboardRow, error) {
+	switch tab {
+	case tabBranches:
+		return branchRows(ctx, b)
+	case tabPullRequests:
+		return pullRequestRows(ctx, b)
+	case tabIssues:
+		return issueRows(ctx, b)
+	case tabCommits:
+		return commitRows(ctx)
+	}
+	return nil, nil
+}
+
+func branchRows(ctx context.Context, b *BacklogRepository) ([]dashboardRow, error) {
+	refToHash, err := b.repo.LsRemoteCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rows []dashboardRow
+	const headsPrefix = "refs/heads/"
+	for ref, hash := range refToHash {
+		if !strings.HasPrefix(ref, headsPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(ref, headsPrefix)
+		rows = append(rows, dashboardRow{
+			title:  name,
+			status: hash[:shortHashLen(hash)],
+			detail: name,
+		})
+	}
+	return rows, nil
+}
+
+func shortHashLen(hash string) int {
+	if len(hash) < 8 {
+		return len(hash)
+	}
+	return 8
+}
+
+func pullRequestRows(ctx context.Context, b *BacklogRepository) ([]dashboardRow, error) {
+	prs, err := b.ListPullRequests(ctx, "open")
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]dashboardRow, 0, len(prs))
+	for _, pr := range prs {
+		rows = append(rows, dashboardRow{
+			title:   pr.Summary,
+			status:  pr.Status.Name,
+			author:  pr.CreatedUser.Name,
+			updated: pr.Updated,
+			detail:  strconv.Itoa(pr.Number),
+		})
+	}
+	return rows, nil
+}
+
+func issueRows(ctx context.Context, b *BacklogRepository) ([]dashboardRow, error) {
+	issues, err := b.ListIssues(ctx, "not_closed")
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]dashboardRow, 0, len(issues))
+	for _, issue := range issues {
+		rows = append(rows, dashboardRow{
+			title:   issue.Summary,
+			status:  issue.Status.Name,
+			updated: issue.Updated,
+			detail:  issue.IssueKey,
+		})
+	}
+	return rows, nil
+}
+
+func commitRows(ctx context.Context) ([]dashboardRow, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "-n", "50", "--format=%h\x1f%an\x1f%ad\x1f%s", "--date=short")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var rows []dashboardRow
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		rows = append(rows, dashboardRow{
+			title:   fields[3],
+			author:  fields[1],
+			updated: fields[2],
+			detail:  fields[0],
+		})
+	}
+	return rows, nil
+}
+
+func (d dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case dashboardRowsMsg:
+		d.rows[msg.tab] = msg.rows
+		d.errs[msg.tab] = msg.err
+		d.loading[msg.tab] = false
+		return d, nil
+	case dashboardDetailMsg:
+		if msg.err != nil {
+			d.detailLines = []string{"error: " + msg.err.Error()}
+			return d, nil
+		}
+		d.detailDiff = msg.diff
+		d.detailLines = make([]string, 0, len(msg.comments))
+		for _, c := range msg.comments {
+			d.detailLines = append(d.detailLines, fmt.Sprintf("%s (%s): %s", c.CreatedUser.Name, c.Created, c.Content))
+		}
+		return d, nil
+	case tea.KeyMsg:
+		return d.updateKey(msg)
+	}
+	return d, nil
+}
+
+func (d dashboard) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if d.filtering {
+		switch msg.Type {
+		case tea.KeyEsc, tea.KeyEnter:
+			d.filtering = false
+		case tea.KeyBackspace:
+			if len(d.filter) > 0 {
+				d.filter = d.filter[:len(d.filter)-1]
+			}
+		case tea.KeyRunes:
+			d.filter += string(msg.Runes)
+		}
+		return d, nil
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return d, tea.Quit
+	case "esc":
+		if d.detail != nil {
+			d.detail = nil
+			return d, nil
+		}
+		return d, tea.Quit
+	case "tab":
+		d.tab = dashboardTabs[(indexOfTab(d.tab)+1)%len(dashboardTabs)]
+		d.filter = ""
+		return d, d.ensureLoaded(d.tab)
+	case "shift+tab":
+		d.tab = dashboardTabs[(indexOfTab(d.tab)+len(dashboardTabs)-1)%len(dashboardTabs)]
+		d.filter = ""
+		return d, d.ensureLoaded(d.tab)
+	case "/":
+		d.filtering = true
+		return d, nil
+	case "j", "down":
+		d.moveCursor(1)
+	case "k", "up":
+		d.moveCursor(-1)
+	case "enter":
+		return d.activate()
+	}
+	return d, nil
+}
+
+func indexOfTab(tab dashboardTab) int {
+	for i, t := range dashboardTabs {
+		if t == tab {
+			return i
+		}
+	}
+	return 0
+}
+
+func (d dashboard) ensureLoaded(tab dashboardTab) tea.Cmd {
+	if _, ok := d.rows[tab]; ok {
+		return nil
+	}
+	if d.loading[tab] {
+		return nil
+	}
+	d.loading[tab] = true
+	return d.loadTab(tab)
+}
+
+func (d dashboard) filteredRows() []dashboardRow {
+	var out []dashboardRow
+	for _, r := range d.rows[d.tab] {
+		if r.matches(d.filter) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (d dashboard) moveCursor(delta int) {
+	rows := d.filteredRows()
+	if len(rows) == 0 {
+		return
+	}
+	c := d.cursor[d.tab] + delta
+	if c < 0 {
+		c = 0
+	}
+	if c > len(rows)-1 {
+		c = len(rows) - 1
+	}
+	d.cursor[d.tab] = c
+}
+
+func (d dashboard) activate() (tea.Model, tea.Cmd) {
+	rows := d.filteredRows()
+	idx := d.cursor[d.tab]
+	if idx < 0 || idx >= len(rows) {
+		return d, nil
+	}
+	row := rows[idx]
+	if d.tab == tabPullRequests {
+		detail := row
+		d.detail = &detail
+		d.detailDiff = nil
+		d.detailLines = nil
+		number, err := strconv.Atoi(row.detail)
+		if err != nil {
+			return d, nil
+		}
+		return d, func() tea.Msg {
+			diff, err := d.backlog.PullRequestDiffSummary(d.ctx, number)
+			if err != nil {
+				return dashboardDetailMsg{err: err}
+			}
+			comments, err := d.backlog.PullRequestComments(d.ctx, number)
+			return dashboardDetailMsg{diff: diff, comments: comments, err: err}
+		}
+	}
+	return d, func() tea.Msg {
+		_ = d.openInBrowser(row)
+		return nil
+	}
+}
+
+type dashboardDetailMsg struct {
+	diff     *backlogapi.DiffSummary
+	comments []backlogapi.Comment
+	err      error
+}
+
+func (d dashboard) openInBrowser(row dashboardRow) error {
+	switch d.tab {
+	case tabBranches:
+		return d.backlog.OpenTree(row.detail)
+	case tabPullRequests:
+		return d.backlog.OpenPullRequestByID(row.detail)
+	case tabIssues:
+		return d.backlog.OpenIssueByID(row.detail)
+	case tabCommits:
+		return d.backlog.OpenCommit(row.detail)
+	}
+	return nil
+}
+
+func (d dashboard) View() string {
+	if d.detail != nil {
+		return d.viewDetail()
+	}
+
+	var s strings.Builder
+	for _, t := range dashboardTabs {
+		if t == d.tab {
+			fmt.Fprintf(&s, "[%s] ", t)
+		} else {
+			fmt.Fprintf(&s, " %s  ", t)
+		}
+	}
+	s.WriteString("\n\n")
+
+	if d.filtering {
+		fmt.Fprintf(&s, "filter: %s\n\n", d.filter)
+	} else if d.filter != "" {
+		fmt.Fprintf(&s, "filter: %s (press / to edit)\n\n", d.filter)
+	}
+
+	if err := d.errs[d.tab]; err != nil {
+		fmt.Fprintf(&s, "error: %s\n", err)
+		return s.String()
+	}
+	if d.loading[d.tab] {
+		s.WriteString("loading...\n")
+		return s.String()
+	}
+
+	rows := d.filteredRows()
+	cursor := d.cursor[d.tab]
+	for i, r := range rows {
+		mark := " "
+		if i == cursor {
+			mark = ">"
+		}
+		fmt.Fprintf(&s, "%s %-40s %-12s %-16s %s\n", mark, truncate(r.title, 40), r.status, r.author, r.updated)
+	}
+
+	s.WriteString("\ntab/shift+tab: switch pane  /: filter  enter: open  q: quit\n")
+	return s.String()
+}
+
+func (d dashboard) viewDetail() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "PR #%s: %s\n\n", d.detail.detail, d.detail.title)
+	if d.detailDiff != nil {
+		total := d.detailDiff.Added + d.detailDiff.Changed + d.detailDiff.Deleted
+		fmt.Fprintf(&s, "%d files changed: %d added, %d changed, %d deleted\n\n", total, d.detailDiff.Added, d.detailDiff.Changed, d.detailDiff.Deleted)
+	}
+	for _, line := range d.detailLines {
+		s.WriteString(line)
+		s.WriteString("\n")
+	}
+	s.WriteString("\nesc: back  q: quit\n")
+	return s.String()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
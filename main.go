@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "gitb:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return nil
+	}
+	switch args[0] {
+	case "pr":
+		return runPR(args[1:])
+	case "issue":
+		return runIssue(args[1:])
+	case "tui":
+		return runTUI(args[1:])
+	case "serve":
+		return runServe(args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return nil
+	default:
+		return errors.Errorf("unknown command %q (see `gitb help`)", args[0])
+	}
+}
+
+func printUsage() {
+	fmt.Println(`gitb is a command-line companion for repositories hosted on Backlog.
+
+Usage:
+  gitb pr create --base <branch> --title <title> [--branch <branch>] [--body <body>]
+  gitb pr list [--status all|open|closed|merged]
+  gitb pr close <number>
+  gitb issue list [--status all|open|in_progress|resolved|closed|not_closed]
+  gitb issue comment --body <text> <key>
+  gitb tui
+  gitb serve [--addr <addr>] [--once] [--poll-interval <duration>] [--hook-script <path>]
+
+Every subcommand also accepts:
+  --remote <name>      remote to use (auto-detected when there's exactly one Backlog remote)
+  --timeout <duration> cancel the operation after this long, e.g. 10s (0 = no timeout)`)
+}
+
+// openBacklogRepository opens the repository in the current directory and
+// resolves its Backlog remote, the way pr/issue/serve need to before they
+// can call the Backlog API or read the remote's refs. It resolves the
+// remote non-interactively: these subcommands are meant to be run from
+// scripts and CI as much as from a terminal, so a multi-remote repo without
+// --remote fails fast instead of blocking on a bubbletea prompt that
+// --timeout and Ctrl-C can't reach. tui is the exception: see runTUI.
+func openBacklogRepository(ctx context.Context, remote string) (*BacklogRepository, error) {
+	remoteName, err := NonInteractiveBacklogRemoteName(".", remote)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := OpenRepositoryCtx(ctx, ".", remoteName)
+	if err != nil {
+		return nil, err
+	}
+	return NewBacklogRepository(repo), nil
+}
+
+func runPR(args []string) error {
+	if len(args) == 0 {
+		return errors.New("gitb pr: expected a subcommand (create, list, close)")
+	}
+	switch args[0] {
+	case "create":
+		return runPRCreate(args[1:])
+	case "list":
+		return runPRList(args[1:])
+	case "close":
+		return runPRClose(args[1:])
+	default:
+		return errors.Errorf("gitb pr: unknown subcommand %q", args[0])
+	}
+}
+
+func runPRCreate(args []string) error {
+	fs := flag.NewFlagSet("gitb pr create", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to use (auto-detected when empty)")
+	timeout := fs.Duration("timeout", 0, "cancel the request after this long (0 = no timeout)")
+	base := fs.String("base", "", "branch to merge into (required)")
+	branch := fs.String("branch", "", "branch to merge from (defaults to the current branch)")
+	title := fs.String("title", "", "pull request title (required)")
+	body := fs.String("body", "", "pull request description")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *base == "" || *title == "" {
+		return errors.New("gitb pr create: --base and --title are required")
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	backlog, err := openBacklogRepository(ctx, *remote)
+	if err != nil {
+		return err
+	}
+	pr, err := backlog.CreatePullRequest(ctx, *base, *branch, *title, *body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created pull request #%d: %s\n", pr.Number, pr.Summary)
+	return nil
+}
+
+func runPRList(args []string) error {
+	fs := flag.NewFlagSet("gitb pr list", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to use (auto-detected when empty)")
+	timeout := fs.Duration("timeout", 0, "cancel the request after this long (0 = no timeout)")
+	status := fs.String("status", "open", "status to filter by: all, open, closed, merged")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	backlog, err := openBacklogRepository(ctx, *remote)
+	if err != nil {
+		return err
+	}
+	prs, err := backlog.ListPullRequests(ctx, *status)
+	if err != nil {
+		return err
+	}
+	for _, pr := range prs {
+		fmt.Printf("#%-5d %-10s %s\n", pr.Number, pr.Status.Name, pr.Summary)
+	}
+	return nil
+}
+
+func runPRClose(args []string) error {
+	fs := flag.NewFlagSet("gitb pr close", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to use (auto-detected when empty)")
+	timeout := fs.Duration("timeout", 0, "cancel the request after this long (0 = no timeout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("gitb pr close: expected a single pull request number")
+	}
+	number, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, "gitb pr close: invalid pull request number")
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	backlog, err := openBacklogRepository(ctx, *remote)
+	if err != nil {
+		return err
+	}
+	if err := backlog.ClosePullRequest(ctx, number); err != nil {
+		return err
+	}
+	fmt.Printf("closed pull request #%d\n", number)
+	return nil
+}
+
+func runIssue(args []string) error {
+	if len(args) == 0 {
+		return errors.New("gitb issue: expected a subcommand (list, comment)")
+	}
+	switch args[0] {
+	case "list":
+		return runIssueList(args[1:])
+	case "comment":
+		return runIssueComment(args[1:])
+	default:
+		return errors.Errorf("gitb issue: unknown subcommand %q", args[0])
+	}
+}
+
+func runIssueList(args []string) error {
+	fs := flag.NewFlagSet("gitb issue list", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to use (auto-detected when empty)")
+	timeout := fs.Duration("timeout", 0, "cancel the request after this long (0 = no timeout)")
+	status := fs.String("status", "not_closed", "status to filter by: all, open, in_progress, resolved, closed, not_closed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	backlog, err := openBacklogRepository(ctx, *remote)
+	if err != nil {
+		return err
+	}
+	issues, err := backlog.ListIssues(ctx, *status)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Printf("%-10s %-10s %s\n", issue.IssueKey, issue.Status.Name, issue.Summary)
+	}
+	return nil
+}
+
+func runIssueComment(args []string) error {
+	fs := flag.NewFlagSet("gitb issue comment", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to use (auto-detected when empty)")
+	timeout := fs.Duration("timeout", 0, "cancel the request after this long (0 = no timeout)")
+	body := fs.String("body", "", "comment text (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("gitb issue comment: expected a single issue key, e.g. PROJ-123")
+	}
+	if *body == "" {
+		return errors.New("gitb issue comment: --body is required")
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	backlog, err := openBacklogRepository(ctx, *remote)
+	if err != nil {
+		return err
+	}
+	return backlog.CommentOnIssue(ctx, fs.Arg(0), *body)
+}
+
+func runTUI(args []string) error {
+	fs := flag.NewFlagSet("gitb tui", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to use (auto-detected when empty, prompted for when ambiguous)")
+	timeout := fs.Duration("timeout", 0, "cancel the session after this long (0 = no timeout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	// Unlike pr/issue, tui is a full-screen interactive session, so it can
+	// let OpenRepositoryCtx fall back to its bubbletea remote selector
+	// instead of requiring --remote on a multi-remote repo.
+	repo, err := OpenRepositoryCtx(ctx, ".", *remote)
+	if err != nil {
+		return err
+	}
+	backlog := NewBacklogRepository(repo)
+	_, err = tea.NewProgram(NewDashboard(ctx, backlog)).Run()
+	return err
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("gitb serve", flag.ExitOnError)
+	remote := fs.String("remote", "", "remote to watch/poll (auto-detected when empty)")
+	timeout := fs.Duration("timeout", 0, "stop serving after this long (0 = run until interrupted)")
+	addr := fs.String("addr", ":8080", "address to listen on for Backlog webhooks")
+	once := fs.Bool("once", false, "poll the remote's refs (ls-remote) on an interval instead of listening for webhooks")
+	pollInterval := fs.Duration("poll-interval", defaultPollInterval, "how often to poll in --once mode")
+	hookScript := fs.String("hook-script", "", "script run on every event: hookScript <event> <detail>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := RootContext(*timeout)
+	defer cancel()
+	return ServeCtx(ctx, ServeOptions{
+		Addr:         *addr,
+		Once:         *once,
+		PollInterval: *pollInterval,
+		HookScript:   *hookScript,
+		Remote:       *remote,
+	})
+}
@@ -6,17 +6,25 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/pkg/errors"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	httptransport "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+
+	"github.com/takuji/gitb/backlogapi"
 )
 
 type Repository interface {
@@ -25,10 +33,38 @@ type Repository interface {
 	RemoteEndpointHost() string
 	RemoteEndpointPath() string
 	RootDirectory() string
-	LsRemote() (RefToHash, error)
+	LsRemoteCtx(ctx context.Context) (RefToHash, error)
+	HeadCommit() (*object.Commit, error)
+	ResolveCommit(hash string) (*object.Commit, error)
+	FetchRefsCtx(ctx context.Context, refs []string) error
+	FetchCtx(ctx context.Context) error
 }
 
-func OpenRepository(path string) (Repository, error) {
+// RootContext builds the context plumbed down from main into every
+// cancellable operation (LsRemoteCtx, BlamePRCtx, ...). It is cancelled
+// either by timeout, if timeout is non-zero, or by SIGINT, so a Ctrl-C
+// during a hanging ls-remote or git subprocess unblocks the caller
+// instead of leaving it stuck.
+func RootContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// OpenRepositoryCtx opens the git repository at path and resolves its
+// Backlog remote. remoteName picks the remote explicitly (the --remote
+// flag / remote: config key); when empty, every remote whose host matches
+// a Backlog domain is considered: zero matches falls back to "origin", one
+// match is used directly, and more than one is resolved interactively
+// through a bubbletea selector (remoteSelector) like the one used for pull
+// requests.
+func OpenRepositoryCtx(ctx context.Context, path, remoteName string) (Repository, error) {
 	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
 		DetectDotGit: true,
 	})
@@ -39,7 +75,19 @@ func OpenRepository(path string) (Repository, error) {
 	if err != nil {
 		return nil, err
 	}
-	remote, err := repo.Remote("origin")
+	if remoteName == "" {
+		remoteName, err = configuredBacklogRemoteName(repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if remoteName == "" {
+		remoteName, err = defaultBacklogRemoteName(repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	remote, err := repo.Remote(remoteName)
 	if err != nil {
 		return nil, err
 	}
@@ -53,16 +101,198 @@ func OpenRepository(path string) (Repository, error) {
 		return nil, err
 	}
 	return &repository{
-		repo: repo,
-		head: head,
-		ep:   ep,
+		repo:       repo,
+		head:       head,
+		remote:     remote,
+		ep:         ep,
+		apiKey:     backlogAPIKeyFromEnv(),
+		sshKeyPath: sshKeyPathFromEnv(),
 	}, nil
 }
 
+// backlogRemoteHostSuffixes are the domains Backlog serves git repositories
+// under; see https://support-ja.backlog.com for the full list of regions.
+var backlogRemoteHostSuffixes = []string{".backlog.com", ".backlog.jp", ".backlogtool.com"}
+
+func isBacklogHost(host string) bool {
+	for _, suffix := range backlogRemoteHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitbConfigSection is the git config section gitb reads its own settings
+// from, e.g. `git config gitb.remote origin`.
+const gitbConfigSection = "gitb"
+
+// configuredBacklogRemoteName reads the remote: config key (`gitb.remote`
+// in the repository's git config), returning "" when it isn't set so
+// callers can fall through to auto-detection.
+func configuredBacklogRemoteName(repo *git.Repository) (string, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	return cfg.Raw.Section(gitbConfigSection).Option("remote"), nil
+}
+
+// backlogRemoteCandidates returns the names of repo's remotes whose URL
+// host looks like a Backlog space, sorted for a stable selector order.
+func backlogRemoteCandidates(repo *git.Repository) ([]string, error) {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, remote := range remotes {
+		cfg := remote.Config()
+		if len(cfg.URLs) == 0 {
+			continue
+		}
+		ep, err := transport.NewEndpoint(cfg.URLs[0])
+		if err != nil {
+			continue
+		}
+		if isBacklogHost(ep.Host) {
+			candidates = append(candidates, cfg.Name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates, nil
+}
+
+// NonInteractiveBacklogRemoteName resolves the remote to use for path
+// without ever prompting: zero Backlog-host matches falls back to
+// "origin", one match is used directly, and more than one is an error
+// asking the caller to pass --remote. It's used by long-running or
+// unattended commands like `gitb serve` where launching the bubbletea
+// selector used by OpenRepositoryCtx would just hang with no TTY attached.
+func NonInteractiveBacklogRemoteName(path, remoteName string) (string, error) {
+	if remoteName != "" {
+		return remoteName, nil
+	}
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	if configured, err := configuredBacklogRemoteName(repo); err != nil {
+		return "", err
+	} else if configured != "" {
+		return configured, nil
+	}
+	candidates, err := backlogRemoteCandidates(repo)
+	if err != nil {
+		return "", err
+	}
+	switch len(candidates) {
+	case 0:
+		return "origin", nil
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", errors.Errorf("multiple Backlog remotes found (%s); pass --remote to pick one", strings.Join(candidates, ", "))
+	}
+}
+
+// defaultBacklogRemoteName resolves which remote to use when the caller
+// didn't pass --remote explicitly.
+func defaultBacklogRemoteName(repo *git.Repository) (string, error) {
+	candidates, err := backlogRemoteCandidates(repo)
+	if err != nil {
+		return "", err
+	}
+	switch len(candidates) {
+	case 0:
+		return "origin", nil
+	case 1:
+		return candidates[0], nil
+	default:
+		return selectRemote(candidates)
+	}
+}
+
+type remoteSelector struct {
+	choices  []string
+	cursor   int
+	selected int
+}
+
+func (s remoteSelector) Init() tea.Cmd {
+	return nil
+}
+
+func (s remoteSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return s, tea.Quit
+		case "j", "down":
+			if s.cursor < len(s.choices)-1 {
+				s.cursor++
+			}
+		case "k", "up":
+			if s.cursor > 0 {
+				s.cursor--
+			}
+		case "enter":
+			s.selected = s.cursor
+			return s, tea.Quit
+		}
+	}
+	return s, nil
+}
+
+func (s remoteSelector) View() string {
+	out := "Multiple Backlog remotes found. Select one:\n\n"
+	for i, choice := range s.choices {
+		cursor := " "
+		if s.cursor == i {
+			cursor = ">"
+		}
+		out += fmt.Sprintf("%s %s\n", cursor, choice)
+	}
+	out += "\nPress 'q' to quit, 'enter' to select.\n"
+	return out
+}
+
+// selectRemote presents names through the bubbletea selector and returns
+// the chosen remote name.
+func selectRemote(names []string) (string, error) {
+	if len(names) == 1 {
+		return names[0], nil
+	}
+	v := remoteSelector{choices: names, cursor: 0, selected: -1}
+	p := tea.NewProgram(v)
+	result, err := p.Run()
+	if err != nil {
+		return "", err
+	}
+	idx := result.(remoteSelector).selected
+	if idx == -1 {
+		os.Exit(0)
+	}
+	return names[idx], nil
+}
+
+// sshKeyPathFromEnv reads the private key used to authenticate LsRemoteCtx
+// over SSH from GITB_SSH_KEY_PATH. It is only consulted for ssh:// remotes;
+// https:// remotes authenticate with the Backlog API key instead.
+func sshKeyPathFromEnv() string {
+	return os.Getenv("GITB_SSH_KEY_PATH")
+}
+
 type repository struct {
-	repo *git.Repository
-	head *plumbing.Reference
-	ep   *transport.Endpoint
+	repo       *git.Repository
+	head       *plumbing.Reference
+	remote     *git.Remote
+	ep         *transport.Endpoint
+	apiKey     string
+	sshKeyPath string
 }
 
 func (r repository) HeadName() string {
@@ -81,6 +311,14 @@ func (r repository) RemoteEndpointPath() string {
 	return r.ep.Path
 }
 
+func (r repository) HeadCommit() (*object.Commit, error) {
+	return r.repo.CommitObject(r.head.Hash())
+}
+
+func (r repository) ResolveCommit(hash string) (*object.Commit, error) {
+	return r.repo.CommitObject(plumbing.NewHash(hash))
+}
+
 func (r repository) RootDirectory() string {
 	wt, err := r.repo.Worktree()
 	if err != nil {
@@ -89,25 +327,121 @@ func (r repository) RootDirectory() string {
 	return wt.Filesystem.Root()
 }
 
-func (r repository) LsRemote() (RefToHash, error) {
-	cmd := exec.Command("git", "ls-remote", "-q")
-	out, err := cmd.Output()
+// LsRemoteCtx lists the remote's refs using go-git's native transport
+// instead of shelling out to a system `git` binary, so gitb keeps working
+// in minimal containers that don't have one, and so a private Backlog
+// repository can be authenticated with an API key even when the user has
+// no git credential helper configured.
+func (r repository) LsRemoteCtx(ctx context.Context) (RefToHash, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	auth, err := r.auth()
+	if err != nil {
+		return nil, err
+	}
+	var refs []*plumbing.Reference
+	err = runCtx(ctx, func() error {
+		var listErr error
+		refs, listErr = r.remote.List(&git.ListOptions{Auth: auth})
+		return listErr
+	})
 	if err != nil {
 		return nil, err
 	}
-	return toRefToHash(out), nil
+	refToHash := make(RefToHash)
+	for _, ref := range refs {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+		refToHash[ref.Name().String()] = ref.Hash().String()
+	}
+	return refToHash, nil
 }
 
-func toRefToHash(b []byte) RefToHash {
-	refToHash := make(RefToHash)
-	remotes := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
-	for _, v := range remotes {
-		delimited := strings.Split(v, "\t")
-		hash := delimited[0]
-		ref := delimited[1]
-		refToHash[ref] = hash
+// runCtx runs fn on a goroutine and waits for either it to finish or ctx
+// to be done, whichever comes first. go-git v4's remote operations
+// (List, Fetch) don't take a context themselves, so this is what makes
+// `--timeout` and Ctrl-C actually able to unblock a caller stuck on a
+// slow or unresponsive Backlog host; fn keeps running in the background
+// until it returns, but the caller isn't forced to wait for it.
+func runCtx(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+// FetchRefsCtx fetches refs from the remote into the local repository
+// under the same names (e.g. "refs/pull/3/head"), so objects that were
+// never covered by the default fetch refspec can be resolved locally
+// afterwards. All refs are fetched in a single round trip. It uses go-git's
+// FetchContext, which is genuinely cancellable unlike List, so it doesn't
+// need the runCtx wrapper.
+func (r repository) FetchRefsCtx(ctx context.Context, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	auth, err := r.auth()
+	if err != nil {
+		return err
+	}
+	refSpecs := make([]config.RefSpec, len(refs))
+	for i, ref := range refs {
+		refSpecs[i] = config.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))
+	}
+	err = r.remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: refSpecs,
+		Auth:     auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// FetchCtx fetches the remote's default refspec (the same one a bare
+// `git fetch` would use) into the local repository, authenticated the same
+// way as LsRemoteCtx/FetchRefsCtx. It's used by `gitb serve` to react to
+// webhook/poll events without shelling out to an unauthenticated `git
+// fetch` that would fail against a private Backlog remote.
+func (r repository) FetchCtx(ctx context.Context) error {
+	auth, err := r.auth()
+	if err != nil {
+		return err
+	}
+	err = r.remote.FetchContext(ctx, &git.FetchOptions{Auth: auth})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// auth builds the transport.AuthMethod used by LsRemoteCtx: HTTP basic
+// auth with the Backlog API key for https:// remotes, or a private key
+// from GITB_SSH_KEY_PATH for ssh:// remotes. It returns a nil AuthMethod
+// (anonymous access) when neither is configured.
+func (r repository) auth() (transport.AuthMethod, error) {
+	switch r.ep.Protocol {
+	case "http", "https":
+		if r.apiKey == "" {
+			return nil, nil
+		}
+		return &httptransport.BasicAuth{Username: "apiKey", Password: r.apiKey}, nil
+	case "ssh":
+		if r.sshKeyPath == "" {
+			return nil, nil
+		}
+		return ssh.NewPublicKeysFromFile("git", r.sshKeyPath, "")
+	default:
+		return nil, nil
 	}
-	return refToHash
 }
 
 func NewBacklogRepository(repo Repository) *BacklogRepository {
@@ -115,6 +449,7 @@ func NewBacklogRepository(repo Repository) *BacklogRepository {
 	projectKey, repoName := extractProjectKeyAndRepoName(repo.RemoteEndpointPath())
 	return &BacklogRepository{
 		openBrowser: openBrowser,
+		apiClient:   backlogapi.NewClient(domain, spaceKey, backlogAPIKeyFromEnv()),
 		repo:        repo,
 		domain:      domain,
 		spaceKey:    spaceKey,
@@ -123,6 +458,14 @@ func NewBacklogRepository(repo Repository) *BacklogRepository {
 	}
 }
 
+// backlogAPIKeyFromEnv reads the API key used for non-browser operations
+// (create/list/close/comment) from BACKLOG_API_KEY. It is read when the
+// repository is opened; an empty key is not an error by itself, only the
+// API requests made with it fail.
+func backlogAPIKeyFromEnv() string {
+	return os.Getenv("BACKLOG_API_KEY")
+}
+
 func extractSpaceKeyAndDomain(host string) (spaceKey, domain string) {
 	delimitedHost := strings.Split(host, ".")
 	spaceKey = delimitedHost[0]
@@ -140,11 +483,14 @@ func extractProjectKeyAndRepoName(path string) (projectKey, repoName string) {
 
 type BacklogRepository struct {
 	openBrowser func(url string) error
+	apiClient   *backlogapi.Client
 	repo        Repository
 	domain      string
 	spaceKey    string
 	projectKey  string
 	repoName    string
+
+	projectID int // numeric project ID, 0 until resolved by resolveProjectID
 }
 
 func (b *BacklogRepository) OpenObject(absPath string, isDirectory bool, line string) error {
@@ -239,6 +585,55 @@ func (b *BacklogRepository) OpenPullRequestList(status string) error {
 		PullRequestListURL(s.Int()))
 }
 
+// ListPullRequests fetches pull requests for status via the Backlog API,
+// as an alternative to OpenPullRequestList that stays in the terminal.
+func (b *BacklogRepository) ListPullRequests(ctx context.Context, status string) ([]backlogapi.PullRequest, error) {
+	s, err := PRStatusFromString(status)
+	if err != nil {
+		return nil, err
+	}
+	return b.apiClient.ListPullRequests(ctx, b.projectKey, b.repoName, s.Int())
+}
+
+// PullRequestComments fetches the comments posted on pull request number,
+// for the dashboard's detail view.
+func (b *BacklogRepository) PullRequestComments(ctx context.Context, number int) ([]backlogapi.Comment, error) {
+	return b.apiClient.ListPullRequestComments(ctx, b.projectKey, b.repoName, number)
+}
+
+// PullRequestDiffSummary fetches the added/changed/deleted file counts for
+// pull request number, for the dashboard's detail view.
+func (b *BacklogRepository) PullRequestDiffSummary(ctx context.Context, number int) (*backlogapi.DiffSummary, error) {
+	return b.apiClient.PullRequestDiffSummary(ctx, b.projectKey, b.repoName, number)
+}
+
+// CreatePullRequest opens a pull request from branch into base via the
+// Backlog API, as an alternative to OpenAddPullRequest that doesn't round
+// -trip through a browser.
+func (b *BacklogRepository) CreatePullRequest(ctx context.Context, base, branch, title, body string) (*backlogapi.PullRequest, error) {
+	if branch == "" {
+		branch = b.repo.HeadShortName()
+	}
+	return b.apiClient.CreatePullRequest(ctx, b.projectKey, b.repoName, backlogapi.CreatePullRequestParams{
+		Base:        base,
+		Branch:      branch,
+		Summary:     title,
+		Description: body,
+	})
+}
+
+// ClosePullRequest moves pull request number to the Closed status via the
+// Backlog API.
+func (b *BacklogRepository) ClosePullRequest(ctx context.Context, number int) error {
+	return b.apiClient.ClosePullRequest(ctx, b.projectKey, b.repoName, number)
+}
+
+// CommentOnPullRequest posts comment on pull request number via the
+// Backlog API.
+func (b *BacklogRepository) CommentOnPullRequest(ctx context.Context, number int, comment string) error {
+	return b.apiClient.AddPullRequestComment(ctx, b.projectKey, b.repoName, number, comment)
+}
+
 type PRStatus int
 
 const (
@@ -277,8 +672,8 @@ func (b *BacklogRepository) OpenPullRequestByID(id string) error {
 		PullRequestURL(id))
 }
 
-func (b *BacklogRepository) OpenPullRequest() error {
-	id, err := b.findPullRequestIDFromRemote(b.repo.HeadName())
+func (b *BacklogRepository) OpenPullRequestCtx(ctx context.Context) error {
+	id, err := b.findPullRequestIDFromRemote(ctx)
 	if err != nil {
 		return err
 	}
@@ -293,40 +688,125 @@ const (
 
 type RefToHash map[string]string
 
-func (b *BacklogRepository) findPullRequestIDFromRemote(ref string) (string, error) {
+// prRelation describes how a candidate PR's head relates to local HEAD,
+// ordered from weakest to strongest match so candidates can be ranked by
+// simply comparing the constant.
+type prRelation int
+
+const (
+	prRelationMergeBase prRelation = iota
+	prRelationAncestor
+	prRelationSameCommit
+)
+
+func (rel prRelation) String() string {
+	switch rel {
+	case prRelationSameCommit:
+		return "same commit as HEAD"
+	case prRelationAncestor:
+		return "ancestor of HEAD"
+	default:
+		return "shares a merge base with HEAD"
+	}
+}
+
+type prCandidate struct {
+	id            string
+	relation      prRelation
+	mergeBaseTime time.Time
+}
+
+// maxPRCandidates bounds how many merge-base candidates are offered in
+// the selector; a rebased branch can otherwise share a distant merge
+// base with dozens of unrelated PR refs.
+const maxPRCandidates = 10
 
-	refToHash, err := b.repo.LsRemote()
+// findPullRequestIDFromRemote finds PR refs related to local HEAD. Unlike
+// a plain hash comparison, this survives `git commit --amend` and rebase:
+// a PR ref counts as related if its head is HEAD itself, an ancestor of
+// HEAD, or shares a merge base with HEAD, ranked in that order and then
+// by how recent the merge base is.
+func (b *BacklogRepository) findPullRequestIDFromRemote(ctx context.Context) (string, error) {
+
+	refToHash, err := b.repo.LsRemoteCtx(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	targetHash, ok := refToHash[ref]
-	if !ok {
-		return "", errors.New("not found a current branch in remote")
+	headCommit, err := b.repo.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+
+	// refs/pull/<id>/head isn't covered by the default fetch refspec, so
+	// most PR refs won't resolve locally on the first try. Rather than
+	// fetching candidates one at a time, find everything that's missing up
+	// front and fetch it all in a single round trip before ranking.
+	var missing []string
+	for prRef, hash := range refToHash {
+		if !isPRRef(prRef) || hash == headCommit.Hash.String() {
+			continue
+		}
+		if _, err := b.repo.ResolveCommit(hash); err != nil {
+			missing = append(missing, prRef)
+		}
 	}
+	if len(missing) > 0 {
+		_ = b.repo.FetchRefsCtx(ctx, missing)
+	}
+
+	var candidates []prCandidate
+	for prRef, hash := range refToHash {
+		if !isPRRef(prRef) {
+			continue
+		}
+		if hash == headCommit.Hash.String() {
+			candidates = append(candidates, prCandidate{id: extractPRID(prRef), relation: prRelationSameCommit})
+			continue
+		}
 
-	var prIDs []string
-	for ref, hash := range refToHash {
-		if !isPRRef(ref) {
+		prCommit, err := b.repo.ResolveCommit(hash)
+		if err != nil {
+			// Still not resolvable after the fetch above (e.g. the ref
+			// moved, or the fetch itself failed); skip it.
 			continue
 		}
-		if hash != targetHash {
+
+		bases, err := prCommit.MergeBase(headCommit)
+		if err != nil || len(bases) == 0 {
 			continue
 		}
-		prIDs = append(prIDs, extractPRID(ref))
+
+		relation := prRelationMergeBase
+		if bases[0].Hash == prCommit.Hash {
+			relation = prRelationAncestor
+		}
+		candidates = append(candidates, prCandidate{
+			id:            extractPRID(prRef),
+			relation:      relation,
+			mergeBaseTime: bases[0].Committer.When,
+		})
 	}
 
-	if len(prIDs) == 0 {
+	if len(candidates) == 0 {
 		return "", errors.New("not found a pull request related to current branch")
 	}
 
-	sort.Sort(sort.Reverse(sort.StringSlice(prIDs)))
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].relation != candidates[j].relation {
+			return candidates[i].relation > candidates[j].relation
+		}
+		return candidates[i].mergeBaseTime.After(candidates[j].mergeBaseTime)
+	})
+	if len(candidates) > maxPRCandidates {
+		candidates = candidates[:maxPRCandidates]
+	}
 
-	return b.selectPR(prIDs)
+	return b.selectPR(candidates)
 }
 
 type prSelector struct {
-	choices  []string
+	choices  []prCandidate
 	cursor   int
 	selected int
 	repoName string
@@ -365,18 +845,18 @@ func (p prSelector) View() string {
 		if p.cursor == i {
 			cursor = ">"
 		}
-		s += fmt.Sprintf("%s %s #%s\n", cursor, p.repoName, choice)
+		s += fmt.Sprintf("%s %s #%s (%s)\n", cursor, p.repoName, choice.id, choice.relation)
 	}
 	s += "\nPress 'q' to quit, 'enter' to select.\n"
 	return s
 }
 
-func (b *BacklogRepository) selectPR(prIDs []string) (string, error) {
-	if len(prIDs) == 1 {
-		return prIDs[0], nil
+func (b *BacklogRepository) selectPR(candidates []prCandidate) (string, error) {
+	if len(candidates) == 1 {
+		return candidates[0].id, nil
 	}
 	v := prSelector{
-		choices:  prIDs,
+		choices:  candidates,
 		cursor:   0,
 		selected: -1,
 		repoName: fmt.Sprintf("%s/%s", b.projectKey, b.repoName),
@@ -390,7 +870,7 @@ func (b *BacklogRepository) selectPR(prIDs []string) (string, error) {
 	if idx == -1 {
 		os.Exit(0)
 	}
-	return prIDs[idx], nil
+	return candidates[idx].id, nil
 }
 
 func isPRRef(ref string) bool {
@@ -431,6 +911,16 @@ func extractIssueKey(s string) string {
 	return matches[1]
 }
 
+// OpenIssueByID opens the issue identified by key (e.g. "PROJ-123") in the
+// browser, for callers that already know the key instead of deriving it
+// from the current branch name the way OpenIssue does.
+func (b *BacklogRepository) OpenIssueByID(key string) error {
+	return b.openBrowser(NewBacklogURLBuilder(b.domain, b.spaceKey).
+		SetProjectKey(b.projectKey).
+		SetRepoName(b.repoName).
+		IssueURL(key))
+}
+
 func (b *BacklogRepository) OpenAddIssue() error {
 	return b.openBrowser(NewBacklogURLBuilder(b.domain, b.spaceKey).
 		SetProjectKey(b.projectKey).
@@ -495,9 +985,56 @@ func (b *BacklogRepository) OpenIssueList(state string) error {
 		IssueListURL(statusIds))
 }
 
-func (b *BacklogRepository) BlamePR(argv []string) error {
+// resolveProjectID returns the numeric Backlog project ID for b.projectKey,
+// resolving and caching it on first use. The issues API's projectId[]
+// parameter only accepts numeric IDs, not project keys, unlike the
+// pull request endpoints which take the key directly in the URL path.
+func (b *BacklogRepository) resolveProjectID(ctx context.Context) (int, error) {
+	if b.projectID != 0 {
+		return b.projectID, nil
+	}
+	project, err := b.apiClient.GetProject(ctx, b.projectKey)
+	if err != nil {
+		return 0, err
+	}
+	b.projectID = project.ID
+	return b.projectID, nil
+}
+
+// ListIssues fetches issues for state via the Backlog API, as an
+// alternative to OpenIssueList that stays in the terminal.
+func (b *BacklogRepository) ListIssues(ctx context.Context, state string) ([]backlogapi.Issue, error) {
+	s, err := IssueStatusFromString(state)
+	if err != nil {
+		return nil, err
+	}
+	var statusIds []int
+	switch s {
+	case IssueStatusAll:
+		// Don't specify the issue status
+	case IssueStatusNotClosed:
+		statusIds = append(statusIds, IssueStatusOpen.Int())
+		statusIds = append(statusIds, IssueStatusInProgress.Int())
+		statusIds = append(statusIds, IssueStatusResolved.Int())
+	default:
+		statusIds = append(statusIds, s.Int())
+	}
+	projectID, err := b.resolveProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.apiClient.ListIssues(ctx, projectID, statusIds)
+}
+
+// CommentOnIssue posts comment on the issue identified by key (e.g.
+// "PROJ-123") via the Backlog API.
+func (b *BacklogRepository) CommentOnIssue(ctx context.Context, key, comment string) error {
+	return b.apiClient.AddIssueComment(ctx, key, comment)
+}
+
+func (b *BacklogRepository) BlamePRCtx(ctx context.Context, argv []string) error {
 	argv = append([]string{"blame", "--first-parent"}, argv...)
-	cmd := exec.CommandContext(context.Background(), "git", argv...)
+	cmd := exec.CommandContext(ctx, "git", argv...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -516,7 +1053,7 @@ func (b *BacklogRepository) BlamePR(argv []string) error {
 		commit, src := commitAndSrc[0], commitAndSrc[1]
 
 		if _, ok := cached[commit]; !ok {
-			pr, err := lookup(commit)
+			pr, err := lookupCtx(ctx, commit)
 			if err != nil {
 				return err
 			}
@@ -535,8 +1072,8 @@ func (b *BacklogRepository) BlamePR(argv []string) error {
 	return err
 }
 
-func lookup(commit string) (string, error) {
-	cmd := exec.CommandContext(context.Background(), "git", "show", "--oneline", commit)
+func lookupCtx(ctx context.Context, commit string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "show", "--oneline", commit)
 	out, err := cmd.Output()
 	if err != nil {
 		return commit, err
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ServeOptions configures `gitb serve`: a long-running process that
+// watches a Backlog repository and reacts to remote changes, either via
+// webhooks pushed to it or, when the host can't expose an endpoint, by
+// polling the remote's refs (ls-remote) on an interval.
+type ServeOptions struct {
+	Addr         string        // HTTP listen address, e.g. ":8080", ignored when Once is set
+	Once         bool          // poll instead of listening for webhooks
+	PollInterval time.Duration // polling interval, only used when Once is set
+	HookScript   string        // optional script run on every event: hookScript <event> <detail>
+	Remote       string        // remote to watch/poll; empty auto-detects
+}
+
+// defaultPollInterval is used by pollCtx when ServeOptions.PollInterval is
+// unset, since time.NewTicker panics on a zero or negative duration.
+const defaultPollInterval = 30 * time.Second
+
+// webhookPayload is the subset of Backlog's webhook JSON schema
+// (https://developer.nulab.com/docs/backlog/webhook/) that gitb reacts
+// to: pull request and push notifications.
+type webhookPayload struct {
+	Type    int `json:"type"`
+	Content struct {
+		PullRequest struct {
+			Number  int    `json:"number"`
+			Summary string `json:"summary"`
+		} `json:"pullRequest"`
+		Comment struct {
+			Content string `json:"content"`
+		} `json:"comment"`
+	} `json:"content"`
+	CreatedUser struct {
+		Name string `json:"name"`
+	} `json:"createdUser"`
+}
+
+// Backlog webhook event types relevant to gitb. The full list is much
+// longer; these are the ones worth reacting to locally.
+const (
+	webhookTypeRepositoryPush          = 12
+	webhookTypePullRequestAdded        = 14
+	webhookTypePullRequestUpdated      = 15
+	webhookTypePullRequestCommentAdded = 16
+	webhookTypePullRequestMerged       = 17
+)
+
+// eventName returns the short identifier passed as the hook script's
+// first argument (see ServeOptions.HookScript).
+func (p webhookPayload) eventName() string {
+	switch p.Type {
+	case webhookTypePullRequestAdded:
+		return "pr-opened"
+	case webhookTypePullRequestUpdated:
+		return "pr-updated"
+	case webhookTypePullRequestCommentAdded:
+		return "pr-commented"
+	case webhookTypePullRequestMerged:
+		return "pr-merged"
+	case webhookTypeRepositoryPush:
+		return "push"
+	default:
+		return fmt.Sprintf("event-%d", p.Type)
+	}
+}
+
+func (p webhookPayload) describe() string {
+	switch p.Type {
+	case webhookTypePullRequestAdded:
+		return fmt.Sprintf("PR #%d opened: %s", p.Content.PullRequest.Number, p.Content.PullRequest.Summary)
+	case webhookTypePullRequestUpdated:
+		return fmt.Sprintf("PR #%d updated: %s", p.Content.PullRequest.Number, p.Content.PullRequest.Summary)
+	case webhookTypePullRequestCommentAdded:
+		return fmt.Sprintf("%s commented on PR #%d: %s", p.CreatedUser.Name, p.Content.PullRequest.Number, p.Content.Comment.Content)
+	case webhookTypePullRequestMerged:
+		return fmt.Sprintf("PR #%d merged: %s", p.Content.PullRequest.Number, p.Content.PullRequest.Summary)
+	case webhookTypeRepositoryPush:
+		return fmt.Sprintf("%s pushed to the repository", p.CreatedUser.Name)
+	default:
+		return fmt.Sprintf("event type %d", p.Type)
+	}
+}
+
+// ServeCtx runs `gitb serve`. It blocks until ctx is cancelled (e.g. by
+// Ctrl-C via RootContext).
+func ServeCtx(ctx context.Context, opts ServeOptions) error {
+	// Resolve the remote up front instead of letting OpenRepositoryCtx fall
+	// back to its interactive selector: serve is meant to run unattended,
+	// where prompting on stdin would just hang with no TTY attached.
+	remoteName, err := NonInteractiveBacklogRemoteName(".", opts.Remote)
+	if err != nil {
+		return err
+	}
+	repo, err := OpenRepositoryCtx(ctx, ".", remoteName)
+	if err != nil {
+		return err
+	}
+
+	if opts.Once {
+		return pollCtx(ctx, opts, repo)
+	}
+	return listenCtx(ctx, opts, repo)
+}
+
+func listenCtx(ctx context.Context, opts ServeOptions, repo Repository) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		react(ctx, opts, repo, payload.eventName(), payload.describe())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := &http.Server{Addr: opts.Addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	fmt.Printf("gitb serve: listening on %s for Backlog webhooks (POST /webhook)\n", opts.Addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return errors.Wrap(err, "webhook server")
+	}
+	return nil
+}
+
+func pollCtx(ctx context.Context, opts ServeOptions, repo Repository) error {
+	last, err := repo.LsRemoteCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fmt.Printf("gitb serve --once: polling ls-remote every %s\n", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := repo.LsRemoteCtx(ctx)
+			if err != nil {
+				fmt.Println("gitb serve: ls-remote failed:", err)
+				continue
+			}
+			for ref, hash := range current {
+				if last[ref] != hash {
+					react(ctx, opts, repo, "ref-update", fmt.Sprintf("%s moved to %s", ref, hash[:8]))
+				}
+			}
+			last = current
+		}
+	}
+}
+
+// react runs the configured reaction to an event: a desktop notification,
+// an authenticated fetch via repo's own transport, and/or the user's hook
+// script. event and detail are passed to the hook script as separate argv
+// entries, per HookScript's doc.
+func react(ctx context.Context, opts ServeOptions, repo Repository, event, detail string) {
+	fmt.Println("gitb serve:", detail)
+	if err := notify("gitb", detail); err != nil {
+		fmt.Println("gitb serve: notification failed:", err)
+	}
+	if err := repo.FetchCtx(ctx); err != nil {
+		fmt.Println("gitb serve: fetch failed:", err)
+	}
+	if opts.HookScript != "" {
+		if err := exec.CommandContext(ctx, opts.HookScript, event, detail).Run(); err != nil {
+			fmt.Println("gitb serve: hook script failed:", err)
+		}
+	}
+}
+
+// notify shows a desktop notification for message, using whichever
+// notifier is available for the current platform.
+func notify(title, message string) error {
+	switch runtime.GOOS {
+	case "linux":
+		return exec.Command("notify-send", title, message).Run()
+	case "darwin":
+		script := fmt.Sprintf(`display notification %q with title %q`, message, title)
+		return exec.Command("osascript", "-e", script).Run()
+	default:
+		return nil
+	}
+}